@@ -4,30 +4,177 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
+// Secret describes one file made available to a container, regardless of
+// which SecretStore resolved it.
 type Secret struct {
 	Name      string
 	IsDir     bool
 	HostBased bool
+
+	// Mode, UID and GID control how SaveTo writes the file out; the zero
+	// value means "root-owned, mode 0400", never the old 0755-for-everything.
+	Mode os.FileMode
+	UID  int
+	GID  int
+
+	// TargetPath is where the secret should land inside the container,
+	// relative to the secrets mount. Defaults to Name when empty.
+	TargetPath string
 }
 
+// SecretData is a Secret together with its resolved content.
 type SecretData struct {
-	Name string
+	Secret
 	Data []byte
 }
 
+// SaveTo writes the secret under dir, honoring Mode/UID/GID. Parent
+// directories are created 0755 regardless (they aren't sensitive); only the
+// leaf file carries the caller's requested permissions, defaulting to a
+// root-owned 0400 rather than the old blanket 0755.
 func (s SecretData) SaveTo(dir string) error {
-	path := filepath.Join(dir, s.Name)
+	target := s.TargetPath
+	if target == "" {
+		target = s.Name
+	}
+
+	path := filepath.Join(dir, target)
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil && !os.IsExist(err) {
 		return err
 	}
-	if err := ioutil.WriteFile(path, s.Data, 0755); err != nil {
+
+	mode := s.Mode
+	if mode == 0 {
+		mode = 0400
+	}
+
+	if err := ioutil.WriteFile(path, s.Data, mode); err != nil {
 		return err
 	}
-	return nil
+
+	return os.Chown(path, s.UID, s.GID)
+}
+
+// SecretStore resolves names (or, for fileStore, arbitrary host paths) into
+// secret content. Multiple stores can be consulted in turn. hostDirStore
+// skips names it finds in none of its directories; fileStore returns an
+// error for a path that does not exist.
+type SecretStore interface {
+	Get(names []string) ([]SecretData, error)
+}
+
+// secretCache avoids re-reading the same secret file on every container
+// start, keyed by source path and invalidated on mtime change.
+type secretCache struct {
+	mu      sync.Mutex
+	entries map[string]secretCacheEntry
+}
+
+type secretCacheEntry struct {
+	mtime time.Time
+	data  []SecretData
+}
+
+func newSecretCache() *secretCache {
+	return &secretCache{entries: make(map[string]secretCacheEntry)}
 }
 
+func (c *secretCache) read(root, name string) ([]SecretData, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		// A directory's mtime only changes when an entry is added or
+		// removed, not when a file already inside it is edited in place, so
+		// caching by it would serve stale contents for directory secrets.
+		return readFile(filepath.Dir(root), name)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[root]
+	c.mu.Unlock()
+
+	if ok && entry.mtime.Equal(info.ModTime()) {
+		return entry.data, nil
+	}
+
+	data, err := readFile(filepath.Dir(root), name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[root] = secretCacheEntry{mtime: info.ModTime(), data: data}
+	c.mu.Unlock()
+
+	return data, nil
+}
+
+// hostDirStore serves named secrets out of one or more configured host
+// directories, replacing the single hard-coded /usr/share/rhel/secrets path.
+type hostDirStore struct {
+	dirs  []string
+	cache *secretCache
+}
+
+// NewHostDirStore returns a SecretStore that looks up each requested name
+// under every directory in dirs, in order, skipping ones that don't exist.
+func NewHostDirStore(dirs []string) SecretStore {
+	return &hostDirStore{dirs: dirs, cache: newSecretCache()}
+}
+
+func (s *hostDirStore) Get(names []string) ([]SecretData, error) {
+	var out []SecretData
+
+	for _, dir := range s.dirs {
+		for _, name := range names {
+			data, err := s.cache.read(filepath.Join(dir, name), name)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			out = append(out, data...)
+		}
+	}
+
+	return out, nil
+}
+
+// fileStore resolves secrets whose "name" is actually an arbitrary absolute
+// host path supplied by the caller, e.g. `docker run --secret src=/path`,
+// rather than a name looked up inside a configured directory.
+type fileStore struct {
+	cache *secretCache
+}
+
+// NewFileStore returns a SecretStore for user-supplied absolute paths.
+func NewFileStore() SecretStore {
+	return &fileStore{cache: newSecretCache()}
+}
+
+func (s *fileStore) Get(paths []string) ([]SecretData, error) {
+	var out []SecretData
+
+	for _, path := range paths {
+		data, err := s.cache.read(path, filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data...)
+	}
+
+	return out, nil
+}
+
+// readAll walks prefix under root, collecting every regular file it finds.
 func readAll(root, prefix string) ([]SecretData, error) {
 	path := filepath.Join(root, prefix)
 
@@ -67,15 +214,12 @@ func readFile(root, name string) ([]SecretData, error) {
 			return nil, err
 		}
 		return dirData, nil
-	} else {
-		bytes, err := ioutil.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		return []SecretData{{Name: name, Data: bytes}}, nil
 	}
-}
 
-func getHostSecretData() ([]SecretData, error) {
-	return readAll("/usr/share/rhel/secrets", "")
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []SecretData{{Secret: Secret{Name: name, IsDir: false, HostBased: true}, Data: bytes}}, nil
 }
@@ -0,0 +1,59 @@
+// +build linux
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// tmpfsSecretMounter stages already-resolved secrets on a per-container
+// tmpfs mount so they never touch the container's writable layer, and
+// disappear as soon as the mount is torn down in Unmount. It is a mount
+// helper, not a SecretStore: it consumes SecretData a store has already
+// resolved, rather than resolving names itself.
+type tmpfsSecretMounter struct {
+	root string // e.g. /var/run/docker/secrets
+}
+
+// NewTmpfsMounter returns a mounter that stages a fresh tmpfs per container
+// under root and writes resolved secrets into it.
+func NewTmpfsMounter(root string) *tmpfsSecretMounter {
+	return &tmpfsSecretMounter{root: root}
+}
+
+// Mount creates and mounts a tmpfs for containerID, writes each secret into
+// it, and returns the host path to bind-mount (read-only) into the
+// container. Callers must pair this with Unmount when the container exits.
+func (m *tmpfsSecretMounter) Mount(containerID string, secrets []SecretData) (string, error) {
+	dir := filepath.Join(m.root, containerID)
+	if err := os.MkdirAll(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	if err := syscall.Mount("tmpfs", dir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0700"); err != nil {
+		return "", err
+	}
+
+	for _, secret := range secrets {
+		if err := secret.SaveTo(dir); err != nil {
+			syscall.Unmount(dir, 0)
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// Unmount tears down the tmpfs created by Mount and removes its now-empty
+// directory.
+func (m *tmpfsSecretMounter) Unmount(containerID string) error {
+	dir := filepath.Join(m.root, containerID)
+
+	if err := syscall.Unmount(dir, 0); err != nil && err != syscall.EINVAL {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
@@ -3,19 +3,40 @@
 package cgroups
 
 import (
+	"fmt"
 	systemd1 "github.com/coreos/go-systemd/dbus"
 	"github.com/dotcloud/docker/pkg/systemd"
 	"github.com/godbus/dbus"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type systemdCgroup struct {
 	cleanupDirs []string
+
+	// freezerPath is the directory Freeze/Thaw write to. On v2 it's set
+	// unconditionally at apply time (it's just the cgroup itself); on v1 it
+	// is set at apply time only when Cgroup.Freezer is given, and otherwise
+	// setFreezerState joins the freezer hierarchy lazily on first use, since a
+	// container doesn't have to opt into a Freezer state
+	// at create time for docker pause/unpause to work on it later.
+	freezerPath string
+
+	// cgroupPath is the path systemd assigned this unit, e.g.
+	// "/system.slice/docker-xxx.scope", the same relative path shared by
+	// every v1 controller mountpoint (or joined under unifiedMountpoint for v2).
+	cgroupPath string
+	unified    bool
+
+	// pid is the container's init process, needed to join any hierarchy
+	// (like freezer on v1) that systemd doesn't set up on its own.
+	pid int
 }
 
 var (
@@ -76,6 +97,10 @@ type KeyValue struct {
 }
 
 func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
+	if IsCgroup2UnifiedMode() {
+		return systemdApplyV2(c, pid)
+	}
+
 	unitName := c.Parent + "-" + c.Name + ".scope"
 	slice := "system.slice"
 	foreground := false
@@ -90,6 +115,11 @@ func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
 		devices    []string
 	)
 
+	hasBlkioArgs := c.BlkioWeight != 0 || c.BlkioLeafWeight != 0 ||
+		len(c.BlkioWeightDevice) != 0 ||
+		len(c.BlkioThrottleReadBpsDevice) != 0 || len(c.BlkioThrottleWriteBpsDevice) != 0 ||
+		len(c.BlkioThrottleReadIOPSDevice) != 0 || len(c.BlkioThrottleWriteIOPSDevice) != 0
+
 	// First set up things not supported by systemd
 
 	if c.Foreground {
@@ -208,6 +238,30 @@ func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
 			systemd1.Property{"CPUShares", dbus.MakeVariant(uint64(c.CpuShares))})
 	}
 
+	if hasBlkioArgs {
+		properties = append(properties,
+			systemd1.Property{"BlockIOAccounting", dbus.MakeVariant(true)})
+	}
+
+	if c.BlkioWeight != 0 {
+		properties = append(properties,
+			systemd1.Property{"BlockIOWeight", dbus.MakeVariant(uint64(c.BlkioWeight))})
+	}
+
+	if c.PidsLimit != 0 {
+		properties = append(properties,
+			systemd1.Property{"TasksAccounting", dbus.MakeVariant(true)},
+			systemd1.Property{"TasksMax", dbus.MakeVariant(tasksMaxValue(c.PidsLimit))})
+	}
+
+	if len(c.UnitProperties) != 0 {
+		var err error
+		properties, err = appendUnitProperties(properties, c.UnitProperties)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if foreground {
 		if len(properties) > 0 {
 			if err := theConn.SetUnitProperties(unitName, true, properties...); err != nil {
@@ -228,6 +282,8 @@ func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
 	}
 
 	cgroup := props["ControlGroup"].(string)
+	res.cgroupPath = cgroup
+	res.pid = pid
 
 	if !c.DeviceAccess {
 		mountpoint, err := FindCgroupMountpoint("devices")
@@ -258,8 +314,8 @@ func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
 				return nil, err
 			}
 
-			if err := writeFile(dir, "devices.deny", "a"); err != nil {
-				return err
+			if err := writeFile(path, "devices.deny", "a"); err != nil {
+				return nil, err
 			}
 		}
 
@@ -368,9 +424,296 @@ func systemdApply(c *Cgroup, pid int) (ActiveCgroup, error) {
 		}
 	}
 
+	if hasBlkioArgs {
+		mountpoint, err := FindCgroupMountpoint("blkio")
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(mountpoint, cgroup)
+
+		if c.BlkioWeight != 0 {
+			if err := writeFile(path, "blkio.weight", strconv.Itoa(int(c.BlkioWeight))); err != nil {
+				return nil, err
+			}
+		}
+
+		if c.BlkioLeafWeight != 0 {
+			if err := writeFile(path, "blkio.leaf_weight", strconv.Itoa(int(c.BlkioLeafWeight))); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, d := range c.BlkioWeightDevice {
+			if err := writeFile(path, "blkio.weight_device", formatDeviceRate(d.Major, d.Minor, d.Weight)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, d := range c.BlkioThrottleReadBpsDevice {
+			if err := writeFile(path, "blkio.throttle.read_bps_device", formatDeviceRate(d.Major, d.Minor, d.Rate)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, d := range c.BlkioThrottleWriteBpsDevice {
+			if err := writeFile(path, "blkio.throttle.write_bps_device", formatDeviceRate(d.Major, d.Minor, d.Rate)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, d := range c.BlkioThrottleReadIOPSDevice {
+			if err := writeFile(path, "blkio.throttle.read_iops_device", formatDeviceRate(d.Major, d.Minor, d.Rate)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, d := range c.BlkioThrottleWriteIOPSDevice {
+			if err := writeFile(path, "blkio.throttle.write_iops_device", formatDeviceRate(d.Major, d.Minor, d.Rate)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.PidsLimit != 0 {
+		// TasksAccounting above should already have systemd create this
+		// hierarchy for us at the same cgroup path as everything else.
+		if mountpoint, err := FindCgroupMountpoint("pids"); err == nil {
+			path := filepath.Join(mountpoint, cgroup)
+
+			if err := writeFile(path, "pids.max", pidsMaxValue(c.PidsLimit)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(c.HugetlbLimit) != 0 {
+		// systemd has no concept of hugetlb accounting, so, like cpuset, we
+		// have to create and join the hierarchy ourselves.
+		if mountpoint, err := FindCgroupMountpoint("hugetlb"); err == nil {
+			supported, err := supportedHugePageSizes(mountpoint)
+			if err != nil {
+				return nil, err
+			}
+
+			path, err := joinHierarchy(mountpoint, cgroup, pid, &res)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, h := range c.HugetlbLimit {
+				if !supported[h.PageSize] {
+					// Not every kernel/distro enables every huge page size.
+					continue
+				}
+
+				if err := writeFile(path, "hugetlb."+h.PageSize+".limit_in_bytes", strconv.FormatUint(h.Limit, 10)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if c.Freezer != "" {
+		if mountpoint, err := FindCgroupMountpoint("freezer"); err == nil {
+			path, err := joinHierarchy(mountpoint, cgroup, pid, &res)
+			if err != nil {
+				return nil, err
+			}
+
+			res.freezerPath = path
+
+			if err := writeFile(path, "freezer.state", c.Freezer); err != nil {
+				return nil, err
+			}
+
+			if err := waitFreezerState(path, c.Freezer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.NetClsClassid != 0 {
+		if mountpoint, err := FindCgroupMountpoint("net_cls"); err == nil {
+			path, err := joinHierarchy(mountpoint, cgroup, pid, &res)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := writeFile(path, "net_cls.classid", fmt.Sprintf("0x%08x", c.NetClsClassid)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(c.NetPrioIfpriomap) != 0 {
+		if mountpoint, err := FindCgroupMountpoint("net_prio"); err == nil {
+			path, err := joinHierarchy(mountpoint, cgroup, pid, &res)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, m := range c.NetPrioIfpriomap {
+				line := fmt.Sprintf("%s %d\n", m.Interface, m.Priority)
+				if err := writeFile(path, "net_prio.ifpriomap", line); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	return &res, nil
 }
 
+// deniedUnitProperties are managed by docker itself (above) and would
+// silently be overridden, or would break container teardown, if a caller
+// could also set them through UnitProperties.
+var deniedUnitProperties = map[string]bool{
+	"Slice":       true,
+	"PIDs":        true,
+	"Description": true,
+}
+
+// appendUnitProperties decodes a Cgroup.UnitProperties list into systemd1
+// properties and appends them to properties, rejecting anything docker
+// already manages itself.
+func appendUnitProperties(properties []systemd1.Property, entries [][2]string) ([]systemd1.Property, error) {
+	for _, kv := range entries {
+		name, tagged := kv[0], kv[1]
+
+		if deniedUnitProperties[name] {
+			return nil, fmt.Errorf("cgroups: unit property %s is managed by docker and cannot be overridden", name)
+		}
+
+		if len(tagged) < 2 || tagged[1] != ':' {
+			return nil, fmt.Errorf("cgroups: unit property %s value %q is missing its s:/u:/b:/t: type prefix", name, tagged)
+		}
+
+		value, err := decodeUnitPropertyValue(tagged[0], tagged[2:])
+		if err != nil {
+			return nil, fmt.Errorf("cgroups: unit property %s: %v", name, err)
+		}
+
+		properties = append(properties, systemd1.Property{name, value})
+	}
+
+	return properties, nil
+}
+
+// decodeUnitPropertyValue turns a tagged UnitProperties value into the dbus
+// type StartTransientUnit/SetUnitProperties expects.
+func decodeUnitPropertyValue(tag byte, raw string) (dbus.Variant, error) {
+	switch tag {
+	case 's':
+		return dbus.MakeVariant(raw), nil
+	case 'u':
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return dbus.Variant{}, err
+		}
+		return dbus.MakeVariant(v), nil
+	case 'b':
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return dbus.Variant{}, err
+		}
+		return dbus.MakeVariant(v), nil
+	case 't':
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return dbus.Variant{}, err
+		}
+		return dbus.MakeVariant(v), nil
+	default:
+		return dbus.Variant{}, fmt.Errorf("unknown type tag %q", string(tag))
+	}
+}
+
+// formatDeviceRate renders the "MAJ:MIN RATE" lines the blkio weight_device
+// and throttle.*_device files expect.
+func formatDeviceRate(major, minor int64, rate uint64) string {
+	return strconv.FormatInt(major, 10) + ":" + strconv.FormatInt(minor, 10) + " " + strconv.FormatUint(rate, 10)
+}
+
+// tasksMaxValue converts a PidsLimit (<=0 meaning unlimited) into the value
+// systemd's TasksMax property expects, where "unlimited" is UINT64_MAX.
+func tasksMaxValue(limit int64) uint64 {
+	if limit <= 0 {
+		return math.MaxUint64
+	}
+	return uint64(limit)
+}
+
+// pidsMaxValue converts a PidsLimit (<=0 meaning unlimited) into the value
+// pids.max expects, where "unlimited" is spelled "max".
+func pidsMaxValue(limit int64) string {
+	if limit <= 0 {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+// supportedHugePageSizes enumerates the huge page sizes the running kernel
+// actually exposes under the hugetlb hierarchy, e.g. {"2MB": true}, by
+// looking at which hugetlb.<size>.limit_in_bytes files exist.
+func supportedHugePageSizes(mountpoint string) (map[string]bool, error) {
+	entries, err := ioutil.ReadDir(mountpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]bool)
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "hugetlb.") && strings.HasSuffix(name, ".limit_in_bytes") {
+			sizes[strings.TrimSuffix(strings.TrimPrefix(name, "hugetlb."), ".limit_in_bytes")] = true
+		}
+	}
+
+	return sizes, nil
+}
+
+// joinHierarchy manually creates and joins cgroup (the same systemd-assigned
+// path used by every other controller, and the path GetStats reads back
+// from) under mountpoint, for controllers (hugetlb, freezer, net_cls,
+// net_prio) that systemd has no accounting property for and so won't set up
+// on its own. The directory is tracked in res.cleanupDirs so Cleanup removes
+// it.
+func joinHierarchy(mountpoint, cgroup string, pid int, res *systemdCgroup) (string, error) {
+	path := filepath.Join(mountpoint, cgroup)
+
+	if err := os.MkdirAll(path, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	res.cleanupDirs = append(res.cleanupDirs, path)
+
+	if err := writeFile(path, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// waitFreezerState polls freezer.state until it reports state, since the
+// transition (especially FROZEN) isn't guaranteed to be synchronous.
+func waitFreezerState(path, state string) error {
+	for i := 0; i < 1000; i++ {
+		data, err := ioutil.ReadFile(filepath.Join(path, "freezer.state"))
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(string(data)) == state {
+			return nil
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return fmt.Errorf("cgroups: timed out waiting for freezer state %s", state)
+}
+
 func (c *systemdCgroup) Cleanup() error {
 	// systemd cleans up, we don't need to do much
 
@@ -380,3 +723,68 @@ func (c *systemdCgroup) Cleanup() error {
 
 	return nil
 }
+
+// Freeze suspends all processes in the container's freezer cgroup, blocking
+// until the kernel reports the transition as complete.
+func (c *systemdCgroup) Freeze() error {
+	return c.setFreezerState("FROZEN")
+}
+
+// Thaw resumes a container previously suspended with Freeze.
+func (c *systemdCgroup) Thaw() error {
+	return c.setFreezerState("THAWED")
+}
+
+// Stats reads back the resource usage systemd and the kernel have recorded
+// for this container since it started.
+func (c *systemdCgroup) Stats() (*Stats, error) {
+	if c.unified {
+		return getStatsV2(filepath.Join(unifiedMountpoint, c.cgroupPath))
+	}
+
+	return GetStats(c.cgroupPath)
+}
+
+// ensureFreezerPath returns the directory Freeze/Thaw should write to,
+// joining the freezer hierarchy on demand if this container wasn't created
+// with an initial Freezer state. On v2, freezerPath is already set at apply
+// time (it's just the cgroup itself), so this is a no-op there.
+func (c *systemdCgroup) ensureFreezerPath() (string, error) {
+	if c.freezerPath != "" {
+		return c.freezerPath, nil
+	}
+
+	mountpoint, err := FindCgroupMountpoint("freezer")
+	if err != nil {
+		return "", err
+	}
+
+	path, err := joinHierarchy(mountpoint, c.cgroupPath, c.pid, c)
+	if err != nil {
+		return "", err
+	}
+
+	c.freezerPath = path
+	return path, nil
+}
+
+func (c *systemdCgroup) setFreezerState(state string) error {
+	path, err := c.ensureFreezerPath()
+	if err != nil {
+		return err
+	}
+
+	if c.unified {
+		value := freezerStateValueV2(state)
+		if err := writeFile(path, "cgroup.freeze", value); err != nil {
+			return err
+		}
+		return waitFreezerStateV2(path, value)
+	}
+
+	if err := writeFile(path, "freezer.state", state); err != nil {
+		return err
+	}
+
+	return waitFreezerState(path, state)
+}
@@ -0,0 +1,121 @@
+// +build linux
+
+package cgroups
+
+import (
+	systemd1 "github.com/coreos/go-systemd/dbus"
+	"testing"
+)
+
+func TestTasksMaxValue(t *testing.T) {
+	cases := []struct {
+		limit int64
+		want  uint64
+	}{
+		{limit: 0, want: ^uint64(0)},
+		{limit: -1, want: ^uint64(0)},
+		{limit: 1, want: 1},
+		{limit: 512, want: 512},
+	}
+
+	for _, tc := range cases {
+		if got := tasksMaxValue(tc.limit); got != tc.want {
+			t.Errorf("tasksMaxValue(%d) = %d, want %d", tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestPidsMaxValue(t *testing.T) {
+	cases := []struct {
+		limit int64
+		want  string
+	}{
+		{limit: 0, want: "max"},
+		{limit: -1, want: "max"},
+		{limit: 1, want: "1"},
+		{limit: 512, want: "512"},
+	}
+
+	for _, tc := range cases {
+		if got := pidsMaxValue(tc.limit); got != tc.want {
+			t.Errorf("pidsMaxValue(%d) = %q, want %q", tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeUnitPropertyValue(t *testing.T) {
+	cases := []struct {
+		tag     byte
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{tag: 's', raw: "hello", want: "hello"},
+		{tag: 'u', raw: "500", want: uint64(500)},
+		{tag: 'u', raw: "not-a-number", wantErr: true},
+		{tag: 'b', raw: "true", want: true},
+		{tag: 'b', raw: "not-a-bool", wantErr: true},
+		{tag: 't', raw: "-5", want: int64(-5)},
+		{tag: 't', raw: "not-a-number", wantErr: true},
+		{tag: 'x', raw: "anything", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		v, err := decodeUnitPropertyValue(tc.tag, tc.raw)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("decodeUnitPropertyValue(%q, %q) = %v, want error", tc.tag, tc.raw, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("decodeUnitPropertyValue(%q, %q) returned error: %v", tc.tag, tc.raw, err)
+			continue
+		}
+		if v.Value() != tc.want {
+			t.Errorf("decodeUnitPropertyValue(%q, %q) = %v, want %v", tc.tag, tc.raw, v.Value(), tc.want)
+		}
+	}
+}
+
+func TestAppendUnitProperties(t *testing.T) {
+	t.Run("denied property", func(t *testing.T) {
+		_, err := appendUnitProperties(nil, [][2]string{{"Slice", "s:foo.slice"}})
+		if err == nil {
+			t.Fatal("expected an error for a denied unit property, got nil")
+		}
+	})
+
+	t.Run("missing type prefix", func(t *testing.T) {
+		_, err := appendUnitProperties(nil, [][2]string{{"OOMScoreAdjust", "500"}})
+		if err == nil {
+			t.Fatal("expected an error for a value missing its type prefix, got nil")
+		}
+	})
+
+	t.Run("bad type tag", func(t *testing.T) {
+		_, err := appendUnitProperties(nil, [][2]string{{"OOMScoreAdjust", "z:500"}})
+		if err == nil {
+			t.Fatal("expected an error for an unknown type tag, got nil")
+		}
+	})
+
+	t.Run("appends onto existing properties", func(t *testing.T) {
+		existing := []systemd1.Property{{Name: "MemoryAccounting"}}
+
+		got, err := appendUnitProperties(existing, [][2]string{{"OOMScoreAdjust", "t:500"}})
+		if err != nil {
+			t.Fatalf("appendUnitProperties returned error: %v", err)
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("len(got) = %d, want 2", len(got))
+		}
+		if got[1].Name != "OOMScoreAdjust" {
+			t.Errorf("got[1].Name = %q, want %q", got[1].Name, "OOMScoreAdjust")
+		}
+		if got[1].Value.Value() != int64(500) {
+			t.Errorf("got[1].Value = %v, want 500", got[1].Value.Value())
+		}
+	})
+}
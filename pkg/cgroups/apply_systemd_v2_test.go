@@ -0,0 +1,42 @@
+// +build linux
+
+package cgroups
+
+import "testing"
+
+func TestCpuSharesToV2Weight(t *testing.T) {
+	cases := []struct {
+		shares int64
+		want   uint64
+	}{
+		{shares: 0, want: 0},
+		{shares: 2, want: 1},
+		{shares: 1024, want: 39},
+		{shares: 262144, want: 10000},
+	}
+
+	for _, tc := range cases {
+		if got := cpuSharesToV2Weight(tc.shares); got != tc.want {
+			t.Errorf("cpuSharesToV2Weight(%d) = %d, want %d", tc.shares, got, tc.want)
+		}
+	}
+}
+
+func TestCpuMaxValue(t *testing.T) {
+	cases := []struct {
+		quota, period int64
+		want          string
+	}{
+		{quota: 0, period: 0, want: "max 100000"},
+		{quota: -1, period: 0, want: "max 100000"},
+		{quota: 0, period: 50000, want: "max 50000"},
+		{quota: 25000, period: 0, want: "25000 100000"},
+		{quota: 25000, period: 50000, want: "25000 50000"},
+	}
+
+	for _, tc := range cases {
+		if got := cpuMaxValue(tc.quota, tc.period); got != tc.want {
+			t.Errorf("cpuMaxValue(%d, %d) = %q, want %q", tc.quota, tc.period, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// +build linux
+
+package cgroups
+
+// Cgroup holds the resource-control configuration for a single container.
+// It is a thin, cgroup-version-agnostic description; Apply (via systemdApply)
+// translates it into whatever the host actually understands.
+type Cgroup struct {
+	Name   string
+	Parent string
+
+	DeviceAccess bool  // Whether to let the container access all devices
+	Memory       int64 // Memory limit (in bytes)
+	MemorySwap   int64 // Total memory usage (memory + swap); set `-1` to disable swap
+	CpuShares    int64 // CPU shares (relative weight vs. other containers)
+	CpusetCpus   string
+	CpusetMems   string
+	CpuQuota     int64
+
+	Slice string // Name of parent slice to put the container in (defaults to system.slice)
+
+	Foreground bool // Use the current cgroup instead of creating a new one
+
+	MemoryAccounting bool
+	CpuAccounting    bool
+
+	BlkioWeight                  uint16
+	BlkioLeafWeight              uint16
+	BlkioWeightDevice            []WeightDevice
+	BlkioThrottleReadBpsDevice   []ThrottleDevice
+	BlkioThrottleWriteBpsDevice  []ThrottleDevice
+	BlkioThrottleReadIOPSDevice  []ThrottleDevice
+	BlkioThrottleWriteIOPSDevice []ThrottleDevice
+
+	PidsLimit int64 // Maximum number of tasks; <= 0 means unlimited
+
+	HugetlbLimit []HugepageLimit
+
+	Freezer string // Desired freezer.state: "FROZEN" or "THAWED"
+
+	NetClsClassid    uint32
+	NetPrioIfpriomap []IfPrioMap
+
+	// UnitProperties passes arbitrary extra systemd unit properties straight
+	// through to StartTransientUnit/SetUnitProperties, for anything this
+	// struct doesn't have a dedicated field for. Each entry is a [key, value]
+	// pair; value carries a one-character type tag ("s:", "u:", "b:" or "t:"
+	// for string/uint64/bool/int64) telling appendUnitProperties which dbus
+	// type to wrap it as, e.g. {"OOMScoreAdjust", "t:500"}.
+	UnitProperties [][2]string
+}
+
+// HugepageLimit caps usage of a single huge page size, e.g. {"2MB", 33554432}.
+type HugepageLimit struct {
+	PageSize string
+	Limit    uint64
+}
+
+// IfPrioMap assigns a net_prio priority to traffic on a single interface.
+type IfPrioMap struct {
+	Interface string
+	Priority  int64
+}
+
+// WeightDevice associates a blkio weight with a single block device,
+// identified the way the kernel identifies it: major:minor.
+type WeightDevice struct {
+	Major  int64
+	Minor  int64
+	Weight uint64
+}
+
+// ThrottleDevice associates a blkio throttle rate (bytes/s or IO/s,
+// depending on which list it's in) with a single block device.
+type ThrottleDevice struct {
+	Major int64
+	Minor int64
+	Rate  uint64
+}
+
+// ActiveCgroup is returned once a Cgroup has been applied to a running
+// process. Callers must call Cleanup when the container exits.
+type ActiveCgroup interface {
+	Cleanup() error
+
+	// Freeze and Thaw drive the freezer cgroup, blocking until the
+	// transition is observed. On v1 they join the freezer hierarchy on first
+	// use if the container was created without a Freezer state; they return
+	// an error if the freezer controller is not mounted.
+	Freeze() error
+	Thaw() error
+
+	// Stats returns a point-in-time snapshot of the container's resource usage.
+	Stats() (*Stats, error)
+}
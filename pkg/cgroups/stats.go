@@ -0,0 +1,214 @@
+// +build linux
+
+package cgroups
+
+import (
+	"path/filepath"
+)
+
+// Stats is a point-in-time snapshot of the resource usage the kernel tracks
+// for a cgroup, normalized the same way whether it was read off a v1
+// per-controller hierarchy or the v2 unified one.
+type Stats struct {
+	CpuStats     CpuStats
+	MemoryStats  MemoryStats
+	BlkioStats   BlkioStats
+	HugetlbStats map[string]HugetlbStats // keyed by page size, e.g. "2MB"
+	PidsStats    PidsStats
+}
+
+// CpuUsage is derived from cpuacct.usage / cpuacct.usage_percpu / cpuacct.stat
+// on v1, or the usage_usec/user_usec/system_usec fields of cpu.stat on v2.
+// All values are nanoseconds.
+type CpuUsage struct {
+	TotalUsage        uint64
+	PercpuUsage       []uint64
+	UsageInKernelmode uint64
+	UsageInUsermode   uint64
+}
+
+// ThrottlingData comes from cpu.stat on both versions (nr_periods,
+// nr_throttled, throttled_time/throttled_usec).
+type ThrottlingData struct {
+	Periods          uint64
+	ThrottledPeriods uint64
+	ThrottledTime    uint64 // nanoseconds
+}
+
+type CpuStats struct {
+	CpuUsage       CpuUsage
+	ThrottlingData ThrottlingData
+}
+
+// MemoryData is one usage/max_usage/failcnt/limit quadruple, reused for the
+// plain, swap and kernel memory counters.
+type MemoryData struct {
+	Usage    uint64
+	MaxUsage uint64
+	Failcnt  uint64
+	Limit    uint64
+}
+
+type MemoryStats struct {
+	Usage  MemoryData
+	Swap   MemoryData
+	Kernel MemoryData
+
+	// Stats holds the raw memory.stat key/value map (cache, rss, ...).
+	Stats map[string]uint64
+}
+
+// BlkioStatEntry is one "MAJ:MIN OP VALUE" line from one of the
+// blkio.throttle.* or blkio.*_recursive files, or the v2 io.stat equivalent.
+type BlkioStatEntry struct {
+	Major uint64
+	Minor uint64
+	Op    string
+	Value uint64
+}
+
+type BlkioStats struct {
+	IoServiceBytesRecursive []BlkioStatEntry
+	IoServicedRecursive     []BlkioStatEntry
+	IoQueuedRecursive       []BlkioStatEntry
+	IoServiceTimeRecursive  []BlkioStatEntry
+	IoWaitTimeRecursive     []BlkioStatEntry
+	IoMergedRecursive       []BlkioStatEntry
+	IoTimeRecursive         []BlkioStatEntry
+	SectorsRecursive        []BlkioStatEntry
+}
+
+type HugetlbStats struct {
+	Usage    uint64
+	MaxUsage uint64
+	Failcnt  uint64
+}
+
+type PidsStats struct {
+	Current uint64
+	Limit   uint64 // 0 means unlimited
+}
+
+// GetStats reads the v1 statistics for the cgroup at cgroupPath, the same
+// systemd-assigned relative path (e.g. "/system.slice/docker-xxx.scope")
+// used across every controller's mountpoint. Controllers that aren't
+// mounted are silently skipped rather than failing the whole call, since
+// many distros disable hugetlb or blkio.
+func GetStats(cgroupPath string) (*Stats, error) {
+	stats := &Stats{HugetlbStats: make(map[string]HugetlbStats)}
+
+	getCpuStats(cgroupPath, stats)
+	getMemoryStats(cgroupPath, stats)
+	getBlkioStats(cgroupPath, stats)
+	getHugetlbStats(cgroupPath, stats)
+	getPidsStats(cgroupPath, stats)
+
+	return stats, nil
+}
+
+// clockTicks is USER_HZ, used to convert cpuacct.stat's tick counts to
+// nanoseconds. It is effectively always 100 on Linux.
+const clockTicks = uint64(100)
+
+const nanosecondsInSecond = uint64(1000000000)
+
+func getCpuStats(cgroupPath string, stats *Stats) {
+	if mountpoint, err := FindCgroupMountpoint("cpuacct"); err == nil {
+		path := filepath.Join(mountpoint, cgroupPath)
+
+		if usage, err := readUint(path, "cpuacct.usage"); err == nil {
+			stats.CpuStats.CpuUsage.TotalUsage = usage
+		}
+		if percpu, err := readUintList(path, "cpuacct.usage_percpu"); err == nil {
+			stats.CpuStats.CpuUsage.PercpuUsage = percpu
+		}
+		if kv, err := readKeyValueFile(path, "cpuacct.stat"); err == nil {
+			stats.CpuStats.CpuUsage.UsageInUsermode = kv["user"] * nanosecondsInSecond / clockTicks
+			stats.CpuStats.CpuUsage.UsageInKernelmode = kv["system"] * nanosecondsInSecond / clockTicks
+		}
+	}
+
+	if mountpoint, err := FindCgroupMountpoint("cpu"); err == nil {
+		path := filepath.Join(mountpoint, cgroupPath)
+
+		if kv, err := readKeyValueFile(path, "cpu.stat"); err == nil {
+			stats.CpuStats.ThrottlingData.Periods = kv["nr_periods"]
+			stats.CpuStats.ThrottlingData.ThrottledPeriods = kv["nr_throttled"]
+			stats.CpuStats.ThrottlingData.ThrottledTime = kv["throttled_time"]
+		}
+	}
+}
+
+func getMemoryStats(cgroupPath string, stats *Stats) {
+	mountpoint, err := FindCgroupMountpoint("memory")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(mountpoint, cgroupPath)
+
+	stats.MemoryStats.Usage = readMemoryData(path, "memory")
+	stats.MemoryStats.Swap = readMemoryData(path, "memory.memsw")
+	stats.MemoryStats.Kernel = readMemoryData(path, "memory.kmem")
+
+	if kv, err := readKeyValueFile(path, "memory.stat"); err == nil {
+		stats.MemoryStats.Stats = kv
+	}
+}
+
+func readMemoryData(path, prefix string) MemoryData {
+	var data MemoryData
+	data.Usage, _ = readUint(path, prefix+".usage_in_bytes")
+	data.MaxUsage, _ = readUint(path, prefix+".max_usage_in_bytes")
+	data.Failcnt, _ = readUint(path, prefix+".failcnt")
+	data.Limit, _ = readUint(path, prefix+".limit_in_bytes")
+	return data
+}
+
+func getBlkioStats(cgroupPath string, stats *Stats) {
+	mountpoint, err := FindCgroupMountpoint("blkio")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(mountpoint, cgroupPath)
+
+	stats.BlkioStats.IoServiceBytesRecursive = readBlkioEntries(path, "blkio.throttle.io_service_bytes")
+	stats.BlkioStats.IoServicedRecursive = readBlkioEntries(path, "blkio.throttle.io_serviced")
+	stats.BlkioStats.IoQueuedRecursive = readBlkioEntries(path, "blkio.io_queued_recursive")
+	stats.BlkioStats.IoServiceTimeRecursive = readBlkioEntries(path, "blkio.io_service_time_recursive")
+	stats.BlkioStats.IoWaitTimeRecursive = readBlkioEntries(path, "blkio.io_wait_time_recursive")
+	stats.BlkioStats.IoMergedRecursive = readBlkioEntries(path, "blkio.io_merged_recursive")
+	stats.BlkioStats.IoTimeRecursive = readBlkioEntries(path, "blkio.io_time_recursive")
+	stats.BlkioStats.SectorsRecursive = readBlkioEntries(path, "blkio.sectors_recursive")
+}
+
+func getHugetlbStats(cgroupPath string, stats *Stats) {
+	mountpoint, err := FindCgroupMountpoint("hugetlb")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(mountpoint, cgroupPath)
+
+	sizes, err := supportedHugePageSizes(mountpoint)
+	if err != nil {
+		return
+	}
+
+	for size := range sizes {
+		var h HugetlbStats
+		h.Usage, _ = readUint(path, "hugetlb."+size+".usage_in_bytes")
+		h.MaxUsage, _ = readUint(path, "hugetlb."+size+".max_usage_in_bytes")
+		h.Failcnt, _ = readUint(path, "hugetlb."+size+".failcnt")
+		stats.HugetlbStats[size] = h
+	}
+}
+
+func getPidsStats(cgroupPath string, stats *Stats) {
+	mountpoint, err := FindCgroupMountpoint("pids")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(mountpoint, cgroupPath)
+
+	stats.PidsStats.Current, _ = readUint(path, "pids.current")
+	stats.PidsStats.Limit = readOptionalMax(path, "pids.max")
+}
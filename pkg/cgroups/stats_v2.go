@@ -0,0 +1,131 @@
+// +build linux
+
+package cgroups
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// getStatsV2 is the cgroup v2 counterpart of GetStats: path is already the
+// absolute directory under the unified hierarchy (unifiedMountpoint joined
+// with the systemd-assigned cgroup), since v2 has no per-controller
+// mountpoints to hunt for.
+func getStatsV2(path string) (*Stats, error) {
+	stats := &Stats{HugetlbStats: make(map[string]HugetlbStats)}
+
+	if kv, err := readKeyValueFile(path, "cpu.stat"); err == nil {
+		// cpu.stat on v2 reports microseconds directly, no clock-tick math needed.
+		stats.CpuStats.CpuUsage.TotalUsage = kv["usage_usec"] * 1000
+		stats.CpuStats.CpuUsage.UsageInUsermode = kv["user_usec"] * 1000
+		stats.CpuStats.CpuUsage.UsageInKernelmode = kv["system_usec"] * 1000
+		stats.CpuStats.ThrottlingData.Periods = kv["nr_periods"]
+		stats.CpuStats.ThrottlingData.ThrottledPeriods = kv["nr_throttled"]
+		stats.CpuStats.ThrottlingData.ThrottledTime = kv["throttled_usec"] * 1000
+	}
+
+	stats.MemoryStats.Usage.Usage, _ = readUint(path, "memory.current")
+	stats.MemoryStats.Usage.Limit = readOptionalMax(path, "memory.max")
+	stats.MemoryStats.Swap.Usage, _ = readUint(path, "memory.swap.current")
+	stats.MemoryStats.Swap.Limit = readOptionalMax(path, "memory.swap.max")
+	if kv, err := readKeyValueFile(path, "memory.stat"); err == nil {
+		stats.MemoryStats.Stats = kv
+	}
+
+	stats.BlkioStats.IoServiceBytesRecursive = readIOStat(path)
+
+	for size := range hugetlbPageSizesV2(path) {
+		// v2 only tracks current usage per page size; there is no
+		// max_usage_in_bytes/failcnt equivalent to the v1 counters.
+		var h HugetlbStats
+		h.Usage, _ = readUint(path, "hugetlb."+size+".current")
+		stats.HugetlbStats[size] = h
+	}
+
+	stats.PidsStats.Current, _ = readUint(path, "pids.current")
+	stats.PidsStats.Limit = readOptionalMax(path, "pids.max")
+
+	return stats, nil
+}
+
+// readIOStat parses io.stat, whose lines look like:
+//
+//	253:0 rbytes=1234 wbytes=5678 rios=1 wios=2 dbytes=0 dios=0
+//
+// into the same BlkioStatEntry shape GetStats derives from
+// blkio.throttle.io_service_bytes on v1.
+func readIOStat(dir string) []BlkioStatEntry {
+	f, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []BlkioStatEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		majMin := strings.SplitN(fields[0], ":", 2)
+		if len(majMin) != 2 {
+			continue
+		}
+		major, err1 := strconv.ParseUint(majMin[0], 10, 64)
+		minor, err2 := strconv.ParseUint(majMin[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		for _, kv := range fields[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			var op string
+			switch parts[0] {
+			case "rbytes":
+				op = "Read"
+			case "wbytes":
+				op = "Write"
+			default:
+				continue
+			}
+
+			value, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			entries = append(entries, BlkioStatEntry{Major: major, Minor: minor, Op: op, Value: value})
+		}
+	}
+
+	return entries
+}
+
+// hugetlbPageSizesV2 enumerates the huge page sizes exposed under the
+// unified hierarchy by looking at which hugetlb.<size>.current files exist.
+func hugetlbPageSizesV2(path string) map[string]bool {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+
+	sizes := make(map[string]bool)
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "hugetlb.") && strings.HasSuffix(name, ".current") {
+			sizes[strings.TrimSuffix(strings.TrimPrefix(name, "hugetlb."), ".current")] = true
+		}
+	}
+
+	return sizes
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package cgroups
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadIOStat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := "253:0 rbytes=1234 wbytes=5678 rios=1 wios=2 dbytes=0 dios=0\n" +
+		"253:16 rbytes=0 wbytes=99 rios=0 wios=3 dbytes=0 dios=0\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "io.stat"), []byte(data), 0644); err != nil {
+		t.Fatalf("writing io.stat: %v", err)
+	}
+
+	got := readIOStat(dir)
+	if len(got) != 4 {
+		t.Fatalf("len(got) = %d, want 4: %+v", len(got), got)
+	}
+
+	want := []BlkioStatEntry{
+		{Major: 253, Minor: 0, Op: "Read", Value: 1234},
+		{Major: 253, Minor: 0, Op: "Write", Value: 5678},
+		{Major: 253, Minor: 16, Op: "Read", Value: 0},
+		{Major: 253, Minor: 16, Op: "Write", Value: 99},
+	}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("got[%d] = %+v, want %+v", i, got[i], w)
+		}
+	}
+}
+
+func TestReadIOStatMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if got := readIOStat(dir); got != nil {
+		t.Errorf("readIOStat on missing file = %v, want nil", got)
+	}
+}
@@ -0,0 +1,228 @@
+// +build linux
+
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FindCgroupMountpoint returns the mountpoint of the cgroup hierarchy that
+// carries the given subsystem, e.g. FindCgroupMountpoint("memory").
+func FindCgroupMountpoint(subsystem string) (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), " ")
+		for _, opt := range strings.Split(fields[len(fields)-1], ",") {
+			if opt == subsystem {
+				return fields[4], nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("cgroup mountpoint not found for %s", subsystem)
+}
+
+// GetThisCgroupDir returns the path (relative to the subsystem's mountpoint)
+// of the cgroup the calling process currently belongs to.
+func GetThisCgroupDir(subsystem string) (string, error) {
+	cgroups, err := parseCgroupFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	return getControllerPath(subsystem, cgroups)
+}
+
+// GetInitCgroupDir returns the path (relative to the subsystem's mountpoint)
+// of the cgroup pid 1 belongs to, used as the root to nest container cgroups under.
+func GetInitCgroupDir(subsystem string) (string, error) {
+	cgroups, err := parseCgroupFile("/proc/1/cgroup")
+	if err != nil {
+		return "", err
+	}
+	return getControllerPath(subsystem, cgroups)
+}
+
+func parseCgroupFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseCgroupFromReader(f)
+}
+
+func parseCgroupFromReader(r io.Reader) (map[string]string, error) {
+	s := bufio.NewScanner(r)
+	cgroups := make(map[string]string)
+
+	for s.Scan() {
+		text := s.Text()
+		parts := strings.SplitN(text, ":", 3)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid cgroup entry: %q", text)
+		}
+		for _, subsystem := range strings.Split(parts[1], ",") {
+			cgroups[subsystem] = parts[2]
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return cgroups, nil
+}
+
+func getControllerPath(subsystem string, cgroups map[string]string) (string, error) {
+	if p, ok := cgroups[subsystem]; ok {
+		return p, nil
+	}
+
+	return "", fmt.Errorf("cgroup subsystem %s not mounted", subsystem)
+}
+
+func writeFile(dir, file, data string) error {
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0700)
+}
+
+// readUint parses a cgroup file holding a single integer, e.g. pids.current.
+func readUint(dir, file string) (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readOptionalMax parses a cgroup file that is either an integer or the
+// literal "max" (pids.max, memory.max, ...), returning 0 for "max".
+func readOptionalMax(dir, file string) uint64 {
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+
+	v := strings.TrimSpace(string(data))
+	if v == "max" {
+		return 0
+	}
+
+	n, _ := strconv.ParseUint(v, 10, 64)
+	return n
+}
+
+// readUintList parses a whitespace-separated list of integers, e.g.
+// cpuacct.usage_percpu.
+func readUintList(dir, file string) ([]uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	out := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// readKeyValueFile parses the "key value\n" format shared by cpuacct.stat,
+// cpu.stat, memory.stat and their v2 equivalents.
+func readKeyValueFile(dir, file string) (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	out := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		out[fields[0]] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// readBlkioEntries parses a blkio.throttle.* or blkio.*_recursive file, whose
+// lines are "MAJ:MIN OP VALUE", "MAJ:MIN VALUE" (Op left empty), or a
+// "Total VALUE" trailer that's skipped.
+func readBlkioEntries(dir, file string) []BlkioStatEntry {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []BlkioStatEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] == "Total" {
+			continue
+		}
+
+		majMin := strings.SplitN(fields[0], ":", 2)
+		if len(majMin) != 2 {
+			continue
+		}
+		major, err := strconv.ParseUint(majMin[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		minor, err := strconv.ParseUint(majMin[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var op, valueField string
+		if len(fields) == 3 {
+			op, valueField = fields[1], fields[2]
+		} else {
+			valueField = fields[1]
+		}
+
+		value, err := strconv.ParseUint(valueField, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, BlkioStatEntry{Major: major, Minor: minor, Op: op, Value: value})
+	}
+
+	return entries
+}
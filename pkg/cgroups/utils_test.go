@@ -0,0 +1,90 @@
+// +build linux
+
+package cgroups
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+func TestReadKeyValueFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, dir, "cpu.stat", "usage_usec 100\nnr_periods 5\ngarbage line\nnr_throttled notanumber\n")
+
+	got, err := readKeyValueFile(dir, "cpu.stat")
+	if err != nil {
+		t.Fatalf("readKeyValueFile returned error: %v", err)
+	}
+
+	want := map[string]uint64{"usage_usec": 100, "nr_periods": 5}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got[%q] = %d, want %d", k, got[k], v)
+		}
+	}
+}
+
+func TestReadKeyValueFileMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := readKeyValueFile(dir, "does-not-exist"); err == nil {
+		t.Fatal("expected an error for a missing file, got nil")
+	}
+}
+
+func TestReadBlkioEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, dir, "blkio.throttle.io_service_bytes", ""+
+		"253:0 Read 1024\n"+
+		"253:0 Write 2048\n"+
+		"Total 3072\n")
+
+	got := readBlkioEntries(dir, "blkio.throttle.io_service_bytes")
+	if len(got) != 2 {
+		t.Fatalf("len(got) = %d, want 2", len(got))
+	}
+	if got[0].Major != 253 || got[0].Minor != 0 || got[0].Op != "Read" || got[0].Value != 1024 {
+		t.Errorf("got[0] = %+v, want {253 0 Read 1024}", got[0])
+	}
+	if got[1].Op != "Write" || got[1].Value != 2048 {
+		t.Errorf("got[1] = %+v, want {253 0 Write 2048}", got[1])
+	}
+}
+
+func TestReadBlkioEntriesMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if got := readBlkioEntries(dir, "does-not-exist"); got != nil {
+		t.Errorf("readBlkioEntries on missing file = %v, want nil", got)
+	}
+}
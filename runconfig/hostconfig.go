@@ -1,20 +1,58 @@
 package runconfig
 
 import (
+	"os"
+
 	"github.com/dotcloud/docker/engine"
 	"github.com/dotcloud/docker/nat"
 	"github.com/dotcloud/docker/utils"
 )
 
+// WeightDevice associates a blkio weight with a single block device, given
+// as its host path (e.g. "/dev/sda").
+type WeightDevice struct {
+	Path   string
+	Weight uint16
+}
+
+// ThrottleDevice associates a blkio throttle rate (bytes/s or IO/s,
+// depending on which HostConfig field it's in) with a single block device.
+type ThrottleDevice struct {
+	Path string
+	Rate uint64
+}
+
+// SecretMount selects one named secret from the daemon's secret stores and
+// says where it should land inside the container.
+type SecretMount struct {
+	Source string // name (or, for a file-based secret, host path) to resolve
+	Target string // path inside the container's secrets mount; defaults to Source
+	Mode   os.FileMode
+	UID    int
+	GID    int
+}
+
 type HostConfig struct {
-	Binds           []string
-	ContainerIDFile string
-	LxcConf         utils.KeyValuePairs
-	Privileged      bool
-	PortBindings    nat.PortMap
-	Links           []string
-	PublishAllPorts bool
-	CliAddress      string
+	Binds                []string
+	ContainerIDFile      string
+	LxcConf              utils.KeyValuePairs
+	Privileged           bool
+	PortBindings         nat.PortMap
+	Links                []string
+	PublishAllPorts      bool
+	CliAddress           string
+	BlkioWeight          uint16
+	BlkioWeightDevice    []WeightDevice
+	BlkioDeviceReadBps   []ThrottleDevice
+	BlkioDeviceWriteBps  []ThrottleDevice
+	BlkioDeviceReadIOps  []ThrottleDevice
+	BlkioDeviceWriteIOps []ThrottleDevice
+	Secrets              []SecretMount
+
+	// SystemdProperties passes arbitrary extra systemd unit properties
+	// through to pkg/cgroups, keyed by property name; see
+	// pkg/cgroups.Cgroup.UnitProperties for the value encoding.
+	SystemdProperties utils.KeyValuePairs
 }
 
 func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
@@ -23,9 +61,17 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 		Privileged:      job.GetenvBool("Privileged"),
 		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
 		CliAddress:      job.Getenv("CliAddress"),
+		BlkioWeight:     uint16(job.GetenvInt("BlkioWeight")),
 	}
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
 	job.GetenvJson("PortBindings", &hostConfig.PortBindings)
+	job.GetenvJson("BlkioWeightDevice", &hostConfig.BlkioWeightDevice)
+	job.GetenvJson("BlkioDeviceReadBps", &hostConfig.BlkioDeviceReadBps)
+	job.GetenvJson("BlkioDeviceWriteBps", &hostConfig.BlkioDeviceWriteBps)
+	job.GetenvJson("BlkioDeviceReadIOps", &hostConfig.BlkioDeviceReadIOps)
+	job.GetenvJson("BlkioDeviceWriteIOps", &hostConfig.BlkioDeviceWriteIOps)
+	job.GetenvJson("Secrets", &hostConfig.Secrets)
+	job.GetenvJson("SystemdProperties", &hostConfig.SystemdProperties)
 	if Binds := job.GetenvList("Binds"); Binds != nil {
 		hostConfig.Binds = Binds
 	}